@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cloudflare/cloudflare-go"
+)
+
+var (
+	// parallel bounds how many record mutations a Provider may have in
+	// flight at once.
+	parallel = 4
+	// failFast, if set, aborts a sync as soon as any record mutation
+	// fails instead of continuing and reporting every failure.
+	failFast = false
+
+	// maxRetries bounds how many times a single Cloudflare API call is
+	// retried after a 429 or 5xx response.
+	maxRetries = 5
+)
+
+// cloudflareProvider is the Provider cfzone has always shipped, now
+// wrapped behind the Provider interface. It is selected by -provider=cloudflare,
+// which is also the default.
+type cloudflareProvider struct {
+	api      *cloudflare.API
+	zoneID   map[string]string
+	viaToken bool
+}
+
+// newCloudflareProvider builds a cloudflareProvider from the CF_API_KEY /
+// CF_API_EMAIL or CF_API_TOKEN environment variables, in the same order
+// of precedence cfzone has always used.
+func newCloudflareProvider() (*cloudflareProvider, error) {
+	if apiKey == "" || apiEmail == "" {
+		if apiToken == "" {
+			return nil, fmt.Errorf("please set CF_API_KEY and CF_API_EMAIL environment variables")
+		}
+		useToken = true
+	}
+
+	httpClient := &http.Client{Transport: newRetryTransport(http.DefaultTransport, maxRetries)}
+
+	var api *cloudflare.API
+	var err error
+	if useToken {
+		api, err = cloudflare.NewWithAPIToken(apiToken, cloudflare.HTTPClient(httpClient))
+	} else {
+		api, err = cloudflare.New(apiKey, apiEmail, cloudflare.HTTPClient(httpClient))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("contacting Cloudflare: %s", err.Error())
+	}
+
+	return &cloudflareProvider{api: api, zoneID: make(map[string]string), viaToken: useToken}, nil
+}
+
+// Preflight verifies that an API token (as opposed to a global key, which
+// already has full account access) is active and scoped with both
+// Zone:Read and DNS:Edit on zone, so a sync fails fast instead of
+// reporting a permission error after already mutating some records.
+func (p *cloudflareProvider) Preflight(ctx context.Context, zone string) error {
+	if !p.viaToken {
+		return nil
+	}
+
+	status, err := p.api.VerifyAPIToken(ctx)
+	if err != nil {
+		return fmt.Errorf("verifying API token: %s", err.Error())
+	}
+
+	if status.Status != "active" {
+		return fmt.Errorf("API token status is %q, not active", status.Status)
+	}
+
+	token, err := p.api.APIToken(ctx, status.ID)
+	if err != nil {
+		// Some tokens aren't scoped to read their own definition back; in
+		// that case we can't enumerate permission groups and fall back to
+		// letting the sync itself surface a missing scope.
+		return nil
+	}
+
+	zoneID, err := p.lookupZoneID(zone)
+	if err != nil {
+		return err
+	}
+
+	var hasZoneRead, hasDNSEdit bool
+	for _, policy := range token.Policies {
+		if !policyCoversZone(policy.Resources, zoneID) {
+			continue
+		}
+
+		for _, group := range policy.PermissionGroups {
+			name := strings.ToLower(group.Name)
+
+			if strings.Contains(name, "zone") && strings.Contains(name, "read") {
+				hasZoneRead = true
+			}
+			if strings.Contains(name, "dns") && (strings.Contains(name, "write") || strings.Contains(name, "edit")) {
+				hasDNSEdit = true
+			}
+		}
+	}
+
+	if !hasZoneRead || !hasDNSEdit {
+		return fmt.Errorf("API token lacks Zone:Read and/or DNS:Edit scope on zone '%s'", zone)
+	}
+
+	return nil
+}
+
+// policyCoversZone reports whether a token policy's resource scope grants
+// access to zoneID, either directly (a "…zone.<zoneID>" resource key) or
+// via the account-wide "all zones" wildcard ("…zone.*").
+func policyCoversZone(resources map[string]interface{}, zoneID string) bool {
+	for resource := range resources {
+		if strings.HasSuffix(resource, ".zone."+zoneID) || strings.HasSuffix(resource, ".zone.*") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lookupZoneID resolves zone to a Cloudflare zone ID, caching the result
+// since every Provider method is handed the zone name rather than the ID.
+func (p *cloudflareProvider) lookupZoneID(zone string) (string, error) {
+	if id, ok := p.zoneID[zone]; ok {
+		return id, nil
+	}
+
+	id, err := p.api.ZoneIDByName(zone)
+	if err != nil {
+		return "", fmt.Errorf("can't get zone ID for '%s': %s", zone, err.Error())
+	}
+
+	p.zoneID[zone] = id
+
+	return id, nil
+}
+
+func (p *cloudflareProvider) GetRecords(ctx context.Context, zone string) (recordCollection, error) {
+	id, err := p.lookupZoneID(zone)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := p.api.DNSRecords(ctx, id, cloudflare.DNSRecord{})
+	if err != nil {
+		return nil, fmt.Errorf("can't get zone records for '%s': %s", id, err.Error())
+	}
+
+	return recordCollection(records), nil
+}
+
+func (p *cloudflareProvider) AppendRecords(ctx context.Context, zone string, records recordCollection) error {
+	id, err := p.lookupZoneID(zone)
+	if err != nil {
+		return err
+	}
+
+	return runConcurrent(records, func(r cloudflare.DNSRecord) error {
+		if _, err := p.api.CreateDNSRecord(ctx, id, r); err != nil {
+			return fmt.Errorf("failed to add record %+v: %s", r, err.Error())
+		}
+		return nil
+	})
+}
+
+func (p *cloudflareProvider) SetRecords(ctx context.Context, zone string, records recordCollection) error {
+	id, err := p.lookupZoneID(zone)
+	if err != nil {
+		return err
+	}
+
+	return runConcurrent(records, func(r cloudflare.DNSRecord) error {
+		if err := p.api.UpdateDNSRecord(ctx, id, r.ID, r); err != nil {
+			return fmt.Errorf("failed to update record %+v: %s", r, err.Error())
+		}
+		return nil
+	})
+}
+
+func (p *cloudflareProvider) DeleteRecords(ctx context.Context, zone string, records recordCollection) error {
+	id, err := p.lookupZoneID(zone)
+	if err != nil {
+		return err
+	}
+
+	return runConcurrent(records, func(r cloudflare.DNSRecord) error {
+		if err := p.api.DeleteDNSRecord(ctx, id, r.ID); err != nil {
+			return fmt.Errorf("failed to delete record %+v: %s", r, err.Error())
+		}
+		return nil
+	})
+}
+
+// runConcurrent applies fn to each record in records using up to
+// parallel workers at once, aggregating every failure into a single
+// error unless failFast is set, in which case it stops dispatching new
+// work as soon as one record fails.
+func runConcurrent(records recordCollection, fn func(cloudflare.DNSRecord) error) error {
+	workers := parallel
+	if workers < 1 {
+		workers = 1
+	}
+
+	sem := make(chan struct{}, workers)
+	errs := make(chan error, len(records))
+	var aborted int32
+	var wg sync.WaitGroup
+
+	for _, r := range records {
+		if failFast && atomic.LoadInt32(&aborted) != 0 {
+			break
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(r cloudflare.DNSRecord) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := fn(r); err != nil {
+				errs <- err
+				if failFast {
+					atomic.StoreInt32(&aborted, 1)
+				}
+			}
+		}(r)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	var failures []string
+	for err := range errs {
+		failures = append(failures, err.Error())
+	}
+
+	if len(failures) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%d record(s) failed:\n%s", len(failures), strings.Join(failures, "\n"))
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/cloudflare/cloudflare-go"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/dnsdisc"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+var (
+	dnsdiscNodes  = ""
+	dnsdiscDomain = ""
+	dnsdiscSeq    = 1
+	dnsdiscKey    = ""
+)
+
+// dnsdiscTTL is the TTL applied to the synthesized tree TXT records.
+// It has no relation to zoneCacheTTL, which is a sentinel zonefile TTL
+// value the zonefile parser interprets as Cloudflare caching (see
+// -cachettl), not a literal record TTL; 600 matches the cfzone-version
+// marker's TTL elsewhere in the codebase.
+const dnsdiscTTL = 600
+
+// runDNSDisc builds an EIP-1459 DNS discovery tree out of the nodes and
+// links listed in dnsdiscNodes, signs it, and syncs the resulting TXT
+// records into the zone's dnsdiscDomain subtree. It reuses the same
+// recordCollection diff engine as the regular zonefile sync, so only
+// subtrees whose hash-named entries actually changed are touched. Like
+// the zonefile sync path, it honours -dryrun (reads the existing records,
+// prints the plan and returns without changing any) and asks for confirmation
+// before mutating unless -yes is set.
+func runDNSDisc(provider Provider, zoneName string) error {
+	if err := validateDNSDiscDomain(dnsdiscDomain, zoneName); err != nil {
+		return err
+	}
+
+	key, err := loadDNSDiscKey()
+	if err != nil {
+		return fmt.Errorf("loading dnsdisc signing key: %s", err.Error())
+	}
+
+	nodes, links, err := parseNodesFile(dnsdiscNodes)
+	if err != nil {
+		return fmt.Errorf("reading %q: %s", dnsdiscNodes, err.Error())
+	}
+
+	tree, err := dnsdisc.MakeTree(uint(dnsdiscSeq), nodes, links)
+	if err != nil {
+		return fmt.Errorf("building discovery tree: %s", err.Error())
+	}
+
+	if _, err := tree.Sign(key, dnsdiscDomain); err != nil {
+		return fmt.Errorf("signing discovery tree: %s", err.Error())
+	}
+
+	treeRecords := make(recordCollection, 0)
+	for name, content := range tree.ToTXT(dnsdiscDomain) {
+		treeRecords = append(treeRecords, cloudflare.DNSRecord{
+			Name:    name,
+			Type:    "TXT",
+			Content: content,
+			TTL:     dnsdiscTTL,
+		})
+	}
+
+	allRecords, err := provider.GetRecords(context.Background(), zoneName)
+	if err != nil {
+		return fmt.Errorf("can't get zone records for %q: %s", zoneName, err.Error())
+	}
+
+	existingRecords := make(recordCollection, 0, len(allRecords))
+	suffix := "." + dnsdiscDomain
+	for _, r := range allRecords {
+		if r.Name == dnsdiscDomain || strings.HasSuffix(r.Name, suffix) {
+			existingRecords = append(existingRecords, r)
+		}
+	}
+
+	addCandidates := treeRecords.Difference(existingRecords, FullMatch)
+	deleteCandidates := existingRecords.Difference(treeRecords, FullMatch)
+	updates := deleteCandidates.Intersect(addCandidates, Updatable)
+	adds := addCandidates.Difference(updates, Updatable)
+	deletes := deleteCandidates.Difference(updates, Updatable)
+
+	numChanges := len(updates) + len(adds) + len(deletes)
+
+	nodesChecksum, err := checksumFile(dnsdiscNodes)
+	if err != nil {
+		return fmt.Errorf("checksumming %q: %s", dnsdiscNodes, err.Error())
+	}
+
+	if dryRun {
+		diff := buildZoneDiff(zoneName, nodesChecksum, deletes, adds, updates, existingRecords)
+
+		return writeZoneDiff(stdout, diff, output)
+	}
+
+	if err := runPreflight(context.Background(), provider, zoneName); err != nil {
+		return fmt.Errorf("preflight check failed: %s", err.Error())
+	}
+
+	if numChanges > 0 && !yes {
+		diff := buildZoneDiff(zoneName, nodesChecksum, deletes, adds, updates, existingRecords)
+		if err := writeZoneDiffText(stdout, diff); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(stdout, "%d change(s). Continue (y/N)? ", numChanges)
+
+		if !yesNo(stdin) {
+			fmt.Fprintf(stdout, "Aborting...\n")
+			return nil
+		}
+	}
+
+	return applyChanges(provider, zoneName, deletes, adds, updates)
+}
+
+// validateDNSDiscDomain rejects a -dnsdiscdomain that isn't a strict
+// subdomain of zoneName. runDNSDisc scopes its existing-records filter
+// to domain and everything under it; pointed at the zone apex (or
+// anything outside the zone), that scope would cover every record in
+// the zone, so every record not in the synthesized tree would be
+// classed as a delete.
+func validateDNSDiscDomain(domain, zoneName string) error {
+	domain = strings.TrimSuffix(domain, ".")
+	zone := strings.TrimSuffix(zoneName, ".")
+
+	if domain == zone {
+		return fmt.Errorf("-dnsdiscdomain %q is the zone apex %q: syncing there would scope the existing-records filter to the whole zone and delete every record not in the discovery tree", domain, zone)
+	}
+
+	if !strings.HasSuffix(domain, "."+zone) {
+		return fmt.Errorf("-dnsdiscdomain %q must be a subdomain of zone %q", domain, zone)
+	}
+
+	return nil
+}
+
+// checksumFile returns the hex-encoded SHA256 checksum of path's
+// contents, used as the dnsdisc equivalent of the zonefile checksum in
+// a rendered plan.
+func checksumFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", hasher.Sum(nil)), nil
+}
+
+// loadDNSDiscKey loads the ECDSA signing key used to sign the discovery
+// tree root, either from the file named by -dnsdisckey or, when that flag
+// is unset, from the CFZONE_DNSDISC_KEY environment variable. A key file
+// that can't be read is an error; the environment variable is then not
+// consulted.
+func loadDNSDiscKey() (*ecdsa.PrivateKey, error) {
+	hexKey := os.Getenv("CFZONE_DNSDISC_KEY")
+
+	if dnsdiscKey != "" {
+		data, err := os.ReadFile(dnsdiscKey)
+		if err != nil {
+			return nil, err
+		}
+		hexKey = strings.TrimSpace(string(data))
+	}
+
+	if hexKey == "" {
+		return nil, fmt.Errorf("no signing key: set -dnsdisckey or CFZONE_DNSDISC_KEY")
+	}
+
+	return crypto.HexToECDSA(strings.TrimPrefix(hexKey, "0x"))
+}
+
+// parseNodesFile reads dnsdiscNodes, accepting either of the two formats
+// the request asks for: an ENR text file (one "enode://" or "enr:"
+// record, or "enrtree://" link, per line; blank lines and "#" comments
+// are ignored), or a devp2p `nodes.json` as written by `devp2p
+// discv4/discv5 crawl`. The format is picked by sniffing the first
+// non-whitespace byte, since nodes.json has no line-oriented fallback.
+// nodes.json carries no link entries, so links is always nil in that
+// case; EIP-1459 link records still have to come from an ENR text file.
+func parseNodesFile(path string) ([]*enode.Node, []string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if trimmed := bytes.TrimLeft(data, " \t\r\n"); len(trimmed) > 0 && trimmed[0] == '{' {
+		return parseNodesJSON(data)
+	}
+
+	return parseNodesText(data)
+}
+
+// parseNodesText parses the ENR text format described on parseNodesFile.
+func parseNodesText(data []byte) ([]*enode.Node, []string, error) {
+	var nodes []*enode.Node
+	var links []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "enrtree://") {
+			links = append(links, line)
+			continue
+		}
+
+		n, err := enode.Parse(enode.ValidSchemes, line)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid node record %q: %s", line, err.Error())
+		}
+		nodes = append(nodes, n)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return nodes, links, nil
+}
+
+// nodesJSONEntry is a single record in a devp2p nodes.json, keyed by
+// node ID in the surrounding map. Only the fields dnsdisc needs are
+// decoded; crawl metadata such as score and response timestamps is
+// ignored.
+type nodesJSONEntry struct {
+	Record string `json:"record"`
+}
+
+// parseNodesJSON parses the devp2p nodes.json format: a JSON object
+// mapping node ID to a record carrying, among other things, the node's
+// ENR text under "record".
+func parseNodesJSON(data []byte) ([]*enode.Node, []string, error) {
+	var set map[string]nodesJSONEntry
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, nil, fmt.Errorf("parsing nodes.json: %s", err.Error())
+	}
+
+	nodes := make([]*enode.Node, 0, len(set))
+	for id, entry := range set {
+		n, err := enode.Parse(enode.ValidSchemes, entry.Record)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid record for node %q: %s", id, err.Error())
+		}
+		nodes = append(nodes, n)
+	}
+
+	return nodes, nil, nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import "testing"
+
+func TestValidateDNSDiscDomain(t *testing.T) {
+	cases := []struct {
+		name    string
+		domain  string
+		zone    string
+		wantErr bool
+	}{
+		{name: "subdomain", domain: "nodes.example.com", zone: "example.com"},
+		{name: "subdomain with trailing dots", domain: "nodes.example.com.", zone: "example.com."},
+		{name: "apex", domain: "example.com", zone: "example.com", wantErr: true},
+		{name: "apex with trailing dot", domain: "example.com.", zone: "example.com", wantErr: true},
+		{name: "unrelated domain", domain: "nodes.other.com", zone: "example.com", wantErr: true},
+		{name: "suffix but not subdomain", domain: "notexample.com", zone: "example.com", wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := validateDNSDiscDomain(c.domain, c.zone)
+			if c.wantErr && err == nil {
+				t.Fatalf("expected error for domain %q, zone %q", c.domain, c.zone)
+			}
+			if !c.wantErr && err != nil {
+				t.Fatalf("unexpected error for domain %q, zone %q: %s", c.domain, c.zone, err.Error())
+			}
+		})
+	}
+}
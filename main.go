@@ -39,6 +39,23 @@ var (
 	origin       = ""
 	zoneAutoTTL  = 0
 	zoneCacheTTL = 1
+
+	// dryRun can be set to true to compute and print the sync plan without
+	// contacting Cloudflare's write endpoints or asking for confirmation.
+	dryRun = false
+	// output selects the rendering of the -dryrun plan: "text", "json" or
+	// "yaml".
+	output = "text"
+
+	// mode selects what cfzone does with its positional argument: "sync"
+	// (the default) treats it as a zonefile path, "dnsdisc" treats it as
+	// the zone name to publish an EIP-1459 discovery tree into.
+	mode = "sync"
+
+	// providerName selects the Provider backend constructed by
+	// newProvider. "cloudflare" is the default and only the Cloudflare
+	// adapter is backward compatible with cfzone's original behaviour.
+	providerName = "cloudflare"
 )
 
 var (
@@ -66,6 +83,21 @@ func parseArguments(args []string) (string, error) {
 	flagset.StringVar(&origin, "origin", "", "Specify origin to resolve '@' at the top level")
 	flagset.IntVar(&zoneAutoTTL, "autottl", 0, "Specify TTL to interpret as Cloudflare automatic")
 	flagset.IntVar(&zoneCacheTTL, "cachettl", 1, "Specify TTL to interpret as Cloudflare caching")
+	flagset.BoolVar(&dryRun, "dryrun", false, "Compute the sync plan and print it without changing anything")
+	flagset.StringVar(&output, "output", "text", "Output format for -dryrun: text, json or yaml")
+	flagset.StringVar(&mode, "mode", "sync", "Operating mode: sync (zonefile) or dnsdisc (EIP-1459 discovery tree)")
+	flagset.StringVar(&dnsdiscNodes, "dnsdiscnodes", "", "dnsdisc mode: path to an ENR text file or a devp2p nodes.json")
+	flagset.StringVar(&dnsdiscDomain, "dnsdiscdomain", "", "dnsdisc mode: apex subdomain to publish the tree under")
+	flagset.IntVar(&dnsdiscSeq, "dnsdiscseq", 1, "dnsdisc mode: sequence number of the published tree")
+	flagset.StringVar(&dnsdiscKey, "dnsdisckey", "", "dnsdisc mode: path to the hex-encoded signing key (defaults to $CFZONE_DNSDISC_KEY)")
+	flagset.StringVar(&providerName, "provider", "cloudflare", "DNS backend to sync to: cloudflare or rfc2136")
+	flagset.StringVar(&rfc2136Server, "rfc2136server", "", "provider=rfc2136: address (host:port) of the authoritative server")
+	flagset.StringVar(&rfc2136TSIGName, "rfc2136tsigname", "", "provider=rfc2136: TSIG key name")
+	flagset.StringVar(&rfc2136TSIGSecret, "rfc2136tsigsecret", "", "provider=rfc2136: base64 TSIG key secret")
+	flagset.IntVar(&parallel, "parallel", 4, "Number of concurrent record mutations")
+	flagset.BoolVar(&failFast, "failfast", false, "Abort on the first failing record instead of reporting every failure")
+	flagset.StringVar(&statePath, "state", "", "Path to a state file used to detect drift since the last sync")
+	flagset.BoolVar(&force, "force", false, "Overwrite drifted records without asking for confirmation")
 	flagset.BoolVar(&printVersion, "version", false, "Print version")
 
 	err := flagset.Parse(args[1:])
@@ -81,6 +113,36 @@ func parseArguments(args []string) (string, error) {
 		flagset.Usage()
 	}
 
+	if err == nil && output != "text" && output != "json" && output != "yaml" {
+		err = fmt.Errorf("Invalid -output %q, must be one of: text, json, yaml", output)
+		fmt.Fprintln(flagset.Output(), err)
+		flagset.Usage()
+	}
+
+	if err == nil && mode != "sync" && mode != "dnsdisc" {
+		err = fmt.Errorf("Invalid -mode %q, must be one of: sync, dnsdisc", mode)
+		fmt.Fprintln(flagset.Output(), err)
+		flagset.Usage()
+	}
+
+	if err == nil && mode == "dnsdisc" && (dnsdiscNodes == "" || dnsdiscDomain == "") {
+		err = errors.New("-mode=dnsdisc requires -dnsdiscnodes and -dnsdiscdomain")
+		fmt.Fprintln(flagset.Output(), err)
+		flagset.Usage()
+	}
+
+	if err == nil && providerName != "cloudflare" && providerName != "rfc2136" {
+		err = fmt.Errorf("Invalid -provider %q, must be one of: cloudflare, rfc2136", providerName)
+		fmt.Fprintln(flagset.Output(), err)
+		flagset.Usage()
+	}
+
+	if err == nil && statePath != "" && providerName != "cloudflare" {
+		err = fmt.Errorf("-state requires -provider=cloudflare: %q records have no stable identity to detect drift against", providerName)
+		fmt.Fprintln(flagset.Output(), err)
+		flagset.Usage()
+	}
+
 	return flagset.Arg(0), err
 }
 
@@ -90,14 +152,21 @@ func main() {
 		os.Exit(1)
 	}
 
-	// If a global key is provided, use it
-	// Otherwise, check for a (scoped) token
-	if apiKey == "" || apiEmail == "" {
-		if apiToken == "" {
-			fmt.Fprintf(stderr, "Please set CF_API_KEY and CF_API_EMAIL environment variables\n")
+	provider, err := newProvider(providerName)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s\n", err.Error())
+		exit(1)
+	}
+
+	if mode == "dnsdisc" {
+		zoneName := path
+
+		if err := runDNSDisc(provider, zoneName); err != nil {
+			fmt.Fprintf(stderr, "%s\n", err.Error())
 			exit(1)
 		}
-		useToken = true
+
+		return
 	}
 
 	f, err := os.Open(path)
@@ -125,26 +194,9 @@ func main() {
 		exit(1)
 	}
 
-	var api *cloudflare.API
-	if useToken {
-		api, err = cloudflare.NewWithAPIToken(apiToken)
-	} else {
-		api, err = cloudflare.New(apiKey, apiEmail)
-	}
-	if err != nil {
-		fmt.Fprintf(stderr, "Error contacting Cloudflare: %s\n", err.Error())
-		exit(1)
-	}
-
-	id, err := api.ZoneIDByName(zoneName)
+	allRecords, err := provider.GetRecords(context.Background(), zoneName)
 	if err != nil {
-		fmt.Fprintf(stderr, "Can't get zone ID for '%s': %s\n", zoneName, err.Error())
-		exit(1)
-	}
-
-	allRecords, err := api.DNSRecords(context.Background(), id, cloudflare.DNSRecord{})
-	if err != nil {
-		fmt.Fprintf(stderr, "Can't get zone records for '%s': %s\n", id, err.Error())
+		fmt.Fprintf(stderr, "Can't get zone records for '%s': %s\n", zoneName, err.Error())
 		exit(1)
 	}
 	var records = make([]cloudflare.DNSRecord, 0, len(allRecords))
@@ -159,6 +211,27 @@ func main() {
 	}
 	existingRecords := recordCollection(records)
 
+	if statePath != "" {
+		previous, err := loadZoneState(statePath)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error reading state file '%s': %s\n", statePath, err.Error())
+			exit(1)
+		}
+
+		if drifted := driftReport(previous, existingRecords); len(drifted) > 0 {
+			writeDriftReport(stdout, drifted)
+
+			if !dryRun && !force {
+				fmt.Fprintf(stdout, "Continue and overwrite drifted records (y/N)? ")
+
+				if !yesNo(stdin) {
+					fmt.Fprintf(stdout, "Aborting...\n")
+					exit(0)
+				}
+			}
+		}
+	}
+
 	versionRecord := cloudflare.DNSRecord{
 		Name:    "cfzone-version." + zoneName,
 		Content: strconv.Itoa(version),
@@ -170,16 +243,26 @@ func main() {
 	if versionRecordFound != nil {
 		deployedVersion, _ := strconv.Atoi(versionRecordFound.Content)
 
-		// Check if we risk "downgrading" the cloudflare setup.
+		// Check if we risk "downgrading" the cloudflare setup. Under
+		// -dryrun this is informational only: the whole point of the flag
+		// is to compute and print the plan without requiring interactive
+		// confirmation, even over closed/empty stdin in a CI pipeline.
 		if deployedVersion > version {
-			fmt.Fprintf(stdout,
-				"Deployed version (%d) is newer than current version (%d). Continue (y/N)? ",
-				deployedVersion,
-				version)
-
-			if !yesNo(stdin) {
-				fmt.Fprintf(stdout, "Aborting...\n")
-				exit(0)
+			if dryRun {
+				fmt.Fprintf(stdout,
+					"Deployed version (%d) is newer than current version (%d).\n",
+					deployedVersion,
+					version)
+			} else {
+				fmt.Fprintf(stdout,
+					"Deployed version (%d) is newer than current version (%d). Continue (y/N)? ",
+					deployedVersion,
+					version)
+
+				if !yesNo(stdin) {
+					fmt.Fprintf(stdout, "Aborting...\n")
+					exit(0)
+				}
 			}
 		}
 
@@ -206,8 +289,46 @@ func main() {
 		deletes = deletes[:0]
 	}
 
+	// Fold the cfzone-version marker in here, before -dryrun and the
+	// preflight check, so both see the mutation a real sync would make
+	// instead of treating it as a free no-op change.
+	// deployedVersionRecord keeps the marker's pre-update state, which was
+	// removed from existingRecords above, for the -dryrun diff's old value.
+	var deployedVersionRecord recordCollection
+	if versionRecordFound != nil {
+		if versionRecordFound.Content != versionRecord.Content {
+			deployedVersionRecord = append(deployedVersionRecord, *versionRecordFound)
+			versionRecordFound.Content = versionRecord.Content
+
+			updates = append(updates, *versionRecordFound)
+		}
+	} else {
+		adds = append(adds, versionRecord)
+	}
+
 	numChanges := len(updates) + len(adds) + len(deletes)
 
+	if dryRun {
+		diff := buildZoneDiff(zoneName, fmt.Sprintf("%x", hasher.Sum(nil)), deletes, adds, updates, append(deployedVersionRecord, existingRecords...))
+
+		if err := writeZoneDiff(stdout, diff, output); err != nil {
+			fmt.Fprintf(stderr, "Error writing diff: %s\n", err.Error())
+			exit(1)
+		}
+
+		exit(0)
+	}
+
+	// Run unconditionally, not just when numChanges > 0, and before any
+	// record is touched, so a permission error never surfaces only after
+	// we've started mutating records. Checked after -dryrun has already exited,
+	// though: a dry run performs no mutations, so it must work with a
+	// least-privilege, read-only token in CI.
+	if err := runPreflight(context.Background(), provider, zoneName); err != nil {
+		fmt.Fprintf(stderr, "Preflight check failed: %s\n", err.Error())
+		exit(1)
+	}
+
 	if numChanges > 0 && !yes {
 		if len(deletes) > 0 {
 			fmt.Fprintf(stdout, "Records to delete:\n")
@@ -242,41 +363,60 @@ func main() {
 		}
 	}
 
-	// We sneak this in after informing the user about updates to avoid
-	// polluting the diff and confusing the user.
-	if versionRecordFound != nil {
-		if versionRecordFound.Content != versionRecord.Content {
-			versionRecordFound.Content = versionRecord.Content
+	if err := applyChanges(provider, zoneName, deletes, adds, updates); err != nil {
+		fmt.Fprintf(stderr, "%s\n", err.Error())
+		exit(1)
+	}
 
-			updates = append(updates, *versionRecordFound)
+	if statePath != "" {
+		refreshed, err := provider.GetRecords(context.Background(), zoneName)
+		if err != nil {
+			fmt.Fprintf(stderr, "Warning: failed to refresh records for state file '%s': %s\n", statePath, err.Error())
+			return
+		}
+
+		st := buildZoneState(zoneName, fmt.Sprintf("%x", hasher.Sum(nil)), refreshed)
+		if err := saveZoneState(statePath, st); err != nil {
+			fmt.Fprintf(stderr, "Warning: failed to write state file '%s': %s\n", statePath, err.Error())
 		}
-	} else {
-		adds = append(addCandidates, versionRecord)
 	}
+}
 
-	for _, r := range deletes {
-		err = api.DeleteDNSRecord(context.Background(), id, r.ID)
-		if err != nil {
-			fmt.Fprintf(stderr, "Failed to delete record %+v: %s\n", r, err.Error())
-			exit(1)
+// applyChanges issues the delete, append and set calls for a computed
+// diff against provider. Unless -failfast is set, it runs every stage
+// even if an earlier one reported failures, so a single run surfaces
+// every failing record instead of stopping at the first.
+func applyChanges(provider Provider, zone string, deletes, adds, updates recordCollection) error {
+	ctx := context.Background()
+
+	var errs []string
+
+	if err := provider.DeleteRecords(ctx, zone, deletes); err != nil {
+		if failFast {
+			return err
 		}
+		errs = append(errs, err.Error())
 	}
 
-	for _, r := range adds {
-		_, err = api.CreateDNSRecord(context.Background(), id, r)
-		if err != nil {
-			fmt.Fprintf(stderr, "Failed to add record %+v: %s\n", r, err.Error())
-			exit(1)
+	if err := provider.AppendRecords(ctx, zone, adds); err != nil {
+		if failFast {
+			return err
 		}
+		errs = append(errs, err.Error())
 	}
 
-	for _, r := range updates {
-		err = api.UpdateDNSRecord(context.Background(), id, r.ID, r)
-		if err != nil {
-			fmt.Fprintf(stderr, "Failed to update record %+v: %s\n", r, err.Error())
-			exit(1)
+	if err := provider.SetRecords(ctx, zone, updates); err != nil {
+		if failFast {
+			return err
 		}
+		errs = append(errs, err.Error())
 	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%s", strings.Join(errs, "\n"))
 }
 
 // yesNo will return true if the user entered Y or y + enter. False in all
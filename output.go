@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/cloudflare/cloudflare-go"
+	"gopkg.in/yaml.v3"
+)
+
+// zoneDiff is the structured, machine-readable representation of a sync
+// plan. It is emitted by -dryrun so that cfzone can be slotted into CI
+// pipelines the way "terraform plan" gates promotion of infrastructure
+// changes.
+type zoneDiff struct {
+	Zone     string       `json:"zone" yaml:"zone"`
+	Checksum string       `json:"sha256" yaml:"sha256"`
+	Adds     []recordDiff `json:"adds,omitempty" yaml:"adds,omitempty"`
+	Deletes  []recordDiff `json:"deletes,omitempty" yaml:"deletes,omitempty"`
+	Updates  []recordDiff `json:"updates,omitempty" yaml:"updates,omitempty"`
+}
+
+// recordDiff describes a single record's change. Old is nil for additions,
+// New is nil for deletions, and both are set for updates.
+type recordDiff struct {
+	Name string       `json:"name" yaml:"name"`
+	Type string       `json:"type" yaml:"type"`
+	Old  *recordState `json:"old,omitempty" yaml:"old,omitempty"`
+	New  *recordState `json:"new,omitempty" yaml:"new,omitempty"`
+}
+
+// recordState is the set of fields that matter for diffing purposes.
+type recordState struct {
+	Content string `json:"content" yaml:"content"`
+	TTL     int    `json:"ttl" yaml:"ttl"`
+	Proxied bool   `json:"proxied" yaml:"proxied"`
+}
+
+func newRecordState(r cloudflare.DNSRecord) *recordState {
+	proxied := false
+	if r.Proxied != nil {
+		proxied = *r.Proxied
+	}
+
+	return &recordState{
+		Content: r.Content,
+		TTL:     r.TTL,
+		Proxied: proxied,
+	}
+}
+
+// buildZoneDiff assembles the structured diff for a planned sync. existing
+// is consulted to find the pre-change state of records being updated.
+func buildZoneDiff(zoneName, checksum string, deletes, adds, updates recordCollection, existing recordCollection) zoneDiff {
+	diff := zoneDiff{
+		Zone:     zoneName,
+		Checksum: checksum,
+	}
+
+	for _, r := range deletes {
+		diff.Deletes = append(diff.Deletes, recordDiff{
+			Name: r.Name,
+			Type: r.Type,
+			Old:  newRecordState(r),
+		})
+	}
+
+	for _, r := range adds {
+		diff.Adds = append(diff.Adds, recordDiff{
+			Name: r.Name,
+			Type: r.Type,
+			New:  newRecordState(r),
+		})
+	}
+
+	for _, r := range updates {
+		d := recordDiff{
+			Name: r.Name,
+			Type: r.Type,
+			New:  newRecordState(r),
+		}
+
+		if _, old := existing.Find(r, Updatable); old != nil {
+			d.Old = newRecordState(*old)
+		}
+
+		diff.Updates = append(diff.Updates, d)
+	}
+
+	return diff
+}
+
+// writeZoneDiff renders diff to w in the requested format.
+func writeZoneDiff(w io.Writer, diff zoneDiff, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(diff)
+	case "yaml":
+		return yaml.NewEncoder(w).Encode(diff)
+	case "text":
+		return writeZoneDiffText(w, diff)
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+}
+
+// writeZoneDiffText renders diff as human-readable text: the zone and
+// checksum, each change with its old and/or new state, and per-kind counts.
+// The dnsdisc confirmation prompt uses it; the zonefile sync prompt prints
+// its own summary.
+func writeZoneDiffText(w io.Writer, diff zoneDiff) error {
+	fmt.Fprintf(w, "Zone: %s\n", diff.Zone)
+	fmt.Fprintf(w, "SHA256 zone checksum: %s\n", diff.Checksum)
+
+	printRecordDiffs(w, "Records to delete", diff.Deletes)
+	printRecordDiffs(w, "Records to add", diff.Adds)
+	printRecordDiffs(w, "Records to update", diff.Updates)
+
+	fmt.Fprintf(w, "Records to delete: %d\n", len(diff.Deletes))
+	fmt.Fprintf(w, "Records to add: %d\n", len(diff.Adds))
+	fmt.Fprintf(w, "Records to update: %d\n", len(diff.Updates))
+
+	return nil
+}
+
+func printRecordDiffs(w io.Writer, label string, records []recordDiff) {
+	if len(records) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "%s:\n", label)
+	for _, r := range records {
+		switch {
+		case r.Old == nil:
+			fmt.Fprintf(w, "  %s %s -> %+v\n", r.Type, r.Name, *r.New)
+		case r.New == nil:
+			fmt.Fprintf(w, "  %s %s: %+v ->\n", r.Type, r.Name, *r.Old)
+		default:
+			fmt.Fprintf(w, "  %s %s: %+v -> %+v\n", r.Type, r.Name, *r.Old, *r.New)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/cloudflare/cloudflare-go"
+)
+
+func boolPtr(b bool) *bool { return &b }
+
+func TestBuildZoneDiff(t *testing.T) {
+	existing := recordCollection{
+		{Name: "www.example.com", Type: "A", Content: "10.0.0.1", TTL: 300, Proxied: boolPtr(true)},
+	}
+	deletes := recordCollection{
+		{Name: "old.example.com", Type: "A", Content: "10.0.0.9", TTL: 300},
+	}
+	adds := recordCollection{
+		{Name: "new.example.com", Type: "A", Content: "10.0.0.2", TTL: 300},
+	}
+	updates := recordCollection{
+		{Name: "www.example.com", Type: "A", Content: "10.0.0.3", TTL: 300, Proxied: boolPtr(true)},
+	}
+
+	diff := buildZoneDiff("example.com", "deadbeef", deletes, adds, updates, existing)
+
+	if diff.Zone != "example.com" || diff.Checksum != "deadbeef" {
+		t.Fatalf("unexpected zone/checksum: %+v", diff)
+	}
+
+	if len(diff.Deletes) != 1 || diff.Deletes[0].New != nil || diff.Deletes[0].Old == nil {
+		t.Fatalf("unexpected delete entry: %+v", diff.Deletes)
+	}
+
+	if len(diff.Adds) != 1 || diff.Adds[0].Old != nil || diff.Adds[0].New == nil {
+		t.Fatalf("unexpected add entry: %+v", diff.Adds)
+	}
+
+	if len(diff.Updates) != 1 {
+		t.Fatalf("expected 1 update, got %d", len(diff.Updates))
+	}
+	u := diff.Updates[0]
+	if u.New == nil || u.New.Content != "10.0.0.3" {
+		t.Fatalf("expected updated content 10.0.0.3, got %+v", u.New)
+	}
+	if u.Old == nil || u.Old.Content != "10.0.0.1" {
+		t.Fatalf("expected old content 10.0.0.1 from existing, got %+v", u.Old)
+	}
+}
+
+func TestWriteZoneDiff(t *testing.T) {
+	diff := zoneDiff{
+		Zone:     "example.com",
+		Checksum: "deadbeef",
+		Adds: []recordDiff{
+			{Name: "new.example.com", Type: "A", New: &recordState{Content: "10.0.0.2", TTL: 300}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := writeZoneDiff(&buf, diff, "json"); err != nil {
+		t.Fatalf("json: unexpected error: %s", err)
+	}
+	if !strings.Contains(buf.String(), `"zone": "example.com"`) {
+		t.Fatalf("json output missing zone field: %s", buf.String())
+	}
+
+	buf.Reset()
+	if err := writeZoneDiff(&buf, diff, "yaml"); err != nil {
+		t.Fatalf("yaml: unexpected error: %s", err)
+	}
+	if !strings.Contains(buf.String(), "zone: example.com") {
+		t.Fatalf("yaml output missing zone field: %s", buf.String())
+	}
+
+	buf.Reset()
+	if err := writeZoneDiff(&buf, diff, "text"); err != nil {
+		t.Fatalf("text: unexpected error: %s", err)
+	}
+	if !strings.Contains(buf.String(), "Records to add: 1") {
+		t.Fatalf("text output missing add count: %s", buf.String())
+	}
+
+	if err := writeZoneDiff(&buf, diff, "xml"); err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+}
+
+func TestNewRecordState(t *testing.T) {
+	r := cloudflare.DNSRecord{Content: "10.0.0.1", TTL: 300, Proxied: boolPtr(true)}
+
+	st := newRecordState(r)
+	if st.Content != "10.0.0.1" || st.TTL != 300 || !st.Proxied {
+		t.Fatalf("unexpected record state: %+v", st)
+	}
+
+	unproxied := newRecordState(cloudflare.DNSRecord{Content: "10.0.0.1"})
+	if unproxied.Proxied {
+		t.Fatal("expected Proxied to default to false when nil")
+	}
+}
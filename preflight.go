@@ -0,0 +1,22 @@
+package main
+
+import "context"
+
+// Preflighter is implemented by providers that can validate, ahead of any
+// mutation, that their credentials are sufficiently scoped to sync zone.
+// Providers that have no such concept (e.g. rfc2136, which authenticates
+// per-request via TSIG) simply don't implement it.
+type Preflighter interface {
+	Preflight(ctx context.Context, zone string) error
+}
+
+// runPreflight calls provider's Preflight check, if it has one, and
+// returns its error. Providers without a Preflighter are assumed fine.
+func runPreflight(ctx context.Context, provider Provider, zone string) error {
+	pf, ok := provider.(Preflighter)
+	if !ok {
+		return nil
+	}
+
+	return pf.Preflight(ctx, zone)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// Provider is cfzone's DNS backend abstraction. Its four methods mirror
+// the shape of github.com/libdns/libdns (GetRecords/AppendRecords/
+// SetRecords/DeleteRecords), but operate on recordCollection since that's
+// the currency the rest of cfzone - the zonefile parser and diff engine
+// in particular - already understands. Adapters are responsible for
+// translating to and from whatever shape their backend actually speaks.
+type Provider interface {
+	// GetRecords returns every record present in zone.
+	GetRecords(ctx context.Context, zone string) (recordCollection, error)
+	// AppendRecords creates records that don't yet exist.
+	AppendRecords(ctx context.Context, zone string, records recordCollection) error
+	// SetRecords updates records previously returned by GetRecords.
+	SetRecords(ctx context.Context, zone string, records recordCollection) error
+	// DeleteRecords removes records previously returned by GetRecords.
+	DeleteRecords(ctx context.Context, zone string, records recordCollection) error
+}
+
+// newProvider constructs the Provider named by -provider.
+func newProvider(name string) (Provider, error) {
+	switch name {
+	case "", "cloudflare":
+		return newCloudflareProvider()
+	case "rfc2136":
+		return newRFC2136Provider()
+	default:
+		return nil, fmt.Errorf("unknown -provider %q, must be one of: cloudflare, rfc2136", name)
+	}
+}
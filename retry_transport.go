@@ -0,0 +1,82 @@
+package main
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// retryTransport wraps an http.RoundTripper, retrying requests that come
+// back with a 429 or 5xx response. It honors a Retry-After header when
+// the server sends one, and otherwise backs off exponentially with
+// jitter, so a burst of rate-limited mutations doesn't abort a sync that
+// would otherwise have succeeded.
+type retryTransport struct {
+	next       http.RoundTripper
+	maxRetries int
+}
+
+func newRetryTransport(next http.RoundTripper, maxRetries int) *retryTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &retryTransport{next: next, maxRetries: maxRetries}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var resp *http.Response
+	var err error
+
+	for attempt := 0; attempt <= t.maxRetries; attempt++ {
+		if attempt > 0 {
+			if req.Body != nil {
+				if req.GetBody == nil {
+					break
+				}
+
+				body, bodyErr := req.GetBody()
+				if bodyErr != nil {
+					break
+				}
+				req.Body = body
+			}
+
+			time.Sleep(retryDelay(resp, attempt))
+		}
+
+		resp, err = t.next.RoundTrip(req)
+		if err != nil {
+			continue
+		}
+
+		if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+			return resp, nil
+		}
+
+		if attempt < t.maxRetries {
+			resp.Body.Close()
+		}
+	}
+
+	return resp, err
+}
+
+// retryDelay computes how long to wait before retrying, honoring a
+// Retry-After header on resp when present and otherwise backing off
+// exponentially (base 500ms) with up to 250ms of jitter.
+func retryDelay(resp *http.Response, attempt int) time.Duration {
+	if resp != nil {
+		if ra := resp.Header.Get("Retry-After"); ra != "" {
+			if secs, err := strconv.Atoi(ra); err == nil {
+				return time.Duration(secs) * time.Second
+			}
+		}
+	}
+
+	backoff := 500 * time.Millisecond << uint(attempt-1)
+	jitter := time.Duration(rand.Int63n(int64(250 * time.Millisecond)))
+
+	return backoff + jitter
+}
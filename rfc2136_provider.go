@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/cloudflare/cloudflare-go"
+	"github.com/miekg/dns"
+)
+
+var (
+	rfc2136Server     = ""
+	rfc2136TSIGName   = ""
+	rfc2136TSIGSecret = ""
+)
+
+// rfc2136Provider implements Provider against an RFC 2136 dynamic-update
+// capable authoritative server (e.g. BIND, PowerDNS, Knot). It is
+// selected by -provider=rfc2136 and reads records via AXFR zone transfer
+// and writes them via UPDATE messages, both TSIG-signed when
+// -rfc2136tsigname is set and unsigned otherwise.
+type rfc2136Provider struct {
+	client   *dns.Client
+	tsigName string
+}
+
+// newRFC2136Provider builds an rfc2136Provider from -rfc2136server and,
+// optionally, -rfc2136tsigname/-rfc2136tsigsecret.
+func newRFC2136Provider() (*rfc2136Provider, error) {
+	if rfc2136Server == "" {
+		return nil, fmt.Errorf("-provider=rfc2136 requires -rfc2136server")
+	}
+
+	p := &rfc2136Provider{client: new(dns.Client)}
+
+	if rfc2136TSIGName != "" {
+		name := dns.Fqdn(rfc2136TSIGName)
+		p.client.TsigSecret = map[string]string{name: rfc2136TSIGSecret}
+		p.tsigName = name
+	}
+
+	return p, nil
+}
+
+func (p *rfc2136Provider) GetRecords(ctx context.Context, zone string) (recordCollection, error) {
+	m := new(dns.Msg)
+	m.SetAxfr(dns.Fqdn(zone))
+	if p.tsigName != "" {
+		m.SetTsig(p.tsigName, dns.HmacSHA256, 300, time.Now().Unix())
+	}
+
+	t := &dns.Transfer{TsigSecret: p.client.TsigSecret}
+
+	env, err := t.In(m, rfc2136Server)
+	if err != nil {
+		return nil, fmt.Errorf("AXFR of '%s' from %s: %s", zone, rfc2136Server, err.Error())
+	}
+
+	var records recordCollection
+	for e := range env {
+		if e.Error != nil {
+			return nil, fmt.Errorf("AXFR of '%s' from %s: %s", zone, rfc2136Server, e.Error.Error())
+		}
+
+		for _, rr := range e.RR {
+			if r, ok := toDNSRecord(rr); ok {
+				records = append(records, r)
+			}
+		}
+	}
+
+	return records, nil
+}
+
+func (p *rfc2136Provider) AppendRecords(ctx context.Context, zone string, records recordCollection) error {
+	return p.update(zone, records, rfc2136OpInsert)
+}
+
+func (p *rfc2136Provider) SetRecords(ctx context.Context, zone string, records recordCollection) error {
+	return p.update(zone, records, rfc2136OpReplace)
+}
+
+func (p *rfc2136Provider) DeleteRecords(ctx context.Context, zone string, records recordCollection) error {
+	return p.update(zone, records, rfc2136OpRemove)
+}
+
+// rfc2136Op selects what update does with each record.
+type rfc2136Op int
+
+const (
+	// rfc2136OpInsert adds a record to its RRset.
+	rfc2136OpInsert rfc2136Op = iota
+	// rfc2136OpReplace removes the record's RRset before inserting the
+	// new value, since a plain Insert only adds to the RRset and would
+	// leave the stale value behind alongside it.
+	rfc2136OpReplace
+	// rfc2136OpRemove removes the record from its RRset.
+	rfc2136OpRemove
+)
+
+// update issues a single RFC 2136 UPDATE message applying op to records.
+func (p *rfc2136Provider) update(zone string, records recordCollection, op rfc2136Op) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	m := new(dns.Msg)
+	m.SetUpdate(dns.Fqdn(zone))
+
+	for _, r := range records {
+		rr, err := fromDNSRecord(r)
+		if err != nil {
+			return err
+		}
+
+		switch op {
+		case rfc2136OpRemove:
+			m.Remove([]dns.RR{rr})
+		case rfc2136OpReplace:
+			m.RemoveRRset([]dns.RR{rr})
+			m.Insert([]dns.RR{rr})
+		default:
+			m.Insert([]dns.RR{rr})
+		}
+	}
+
+	if p.tsigName != "" {
+		m.SetTsig(p.tsigName, dns.HmacSHA256, 300, time.Now().Unix())
+	}
+
+	resp, _, err := p.client.Exchange(m, rfc2136Server)
+	if err != nil {
+		return fmt.Errorf("RFC2136 update of '%s': %s", zone, err.Error())
+	}
+	if resp.Rcode != dns.RcodeSuccess {
+		return fmt.Errorf("RFC2136 update of '%s' rejected: %s", zone, dns.RcodeToString[resp.Rcode])
+	}
+
+	return nil
+}
+
+// toDNSRecord converts an RR seen during AXFR into cfzone's record shape.
+// Only the RR types cfzone's zonefile parser understands are translated;
+// anything else (SOA, RRSIG, ...) is dropped.
+func toDNSRecord(rr dns.RR) (cloudflare.DNSRecord, bool) {
+	hdr := rr.Header()
+	r := cloudflare.DNSRecord{
+		Name: strings.TrimSuffix(hdr.Name, "."),
+		TTL:  int(hdr.Ttl),
+	}
+
+	switch v := rr.(type) {
+	case *dns.A:
+		r.Type, r.Content = "A", v.A.String()
+	case *dns.AAAA:
+		r.Type, r.Content = "AAAA", v.AAAA.String()
+	case *dns.CNAME:
+		r.Type, r.Content = "CNAME", strings.TrimSuffix(v.Target, ".")
+	case *dns.TXT:
+		r.Type, r.Content = "TXT", strings.Join(v.Txt, "")
+	case *dns.NS:
+		r.Type, r.Content = "NS", strings.TrimSuffix(v.Ns, ".")
+	default:
+		return cloudflare.DNSRecord{}, false
+	}
+
+	return r, true
+}
+
+// fromDNSRecord converts a cfzone record into an RR suitable for an RFC
+// 2136 update message.
+func fromDNSRecord(r cloudflare.DNSRecord) (dns.RR, error) {
+	hdr := dns.RR_Header{
+		Name:   dns.Fqdn(r.Name),
+		Rrtype: dns.StringToType[r.Type],
+		Class:  dns.ClassINET,
+		Ttl:    uint32(r.TTL),
+	}
+
+	switch r.Type {
+	case "A":
+		return &dns.A{Hdr: hdr, A: net.ParseIP(r.Content)}, nil
+	case "AAAA":
+		return &dns.AAAA{Hdr: hdr, AAAA: net.ParseIP(r.Content)}, nil
+	case "CNAME":
+		return &dns.CNAME{Hdr: hdr, Target: dns.Fqdn(r.Content)}, nil
+	case "TXT":
+		return &dns.TXT{Hdr: hdr, Txt: []string{r.Content}}, nil
+	case "NS":
+		return &dns.NS{Hdr: hdr, Ns: dns.Fqdn(r.Content)}, nil
+	default:
+		return nil, fmt.Errorf("record type %q is not supported by -provider=rfc2136", r.Type)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/cloudflare/cloudflare-go"
+)
+
+var (
+	// statePath is the path (via -state) to persist sync state to after
+	// each successful run, enabling drift detection on the next one.
+	statePath = ""
+	// force, if set, overwrites drifted records without asking for
+	// confirmation first.
+	force = false
+)
+
+// zoneState is the persisted shape of a successful sync: enough to tell,
+// on the next run, whether any record was changed at Cloudflare outside
+// of cfzone.
+type zoneState struct {
+	Zone     string        `json:"zone"`
+	Checksum string        `json:"sha256"`
+	Version  int           `json:"version"`
+	Records  []stateRecord `json:"records"`
+}
+
+// stateRecord is the subset of a DNS record's fields that matter for
+// drift detection.
+type stateRecord struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	TTL     int    `json:"ttl"`
+	Proxied bool   `json:"proxied"`
+}
+
+func newStateRecord(r cloudflare.DNSRecord) stateRecord {
+	proxied := false
+	if r.Proxied != nil {
+		proxied = *r.Proxied
+	}
+
+	return stateRecord{
+		ID:      r.ID,
+		Type:    r.Type,
+		Name:    r.Name,
+		Content: r.Content,
+		TTL:     r.TTL,
+		Proxied: proxied,
+	}
+}
+
+// buildZoneState captures records as the state to persist after a
+// successful sync of zoneName.
+func buildZoneState(zoneName, checksum string, records recordCollection) zoneState {
+	st := zoneState{
+		Zone:     zoneName,
+		Checksum: checksum,
+		Version:  version,
+	}
+
+	for _, r := range records {
+		st.Records = append(st.Records, newStateRecord(r))
+	}
+
+	return st
+}
+
+// loadZoneState reads a previously written state file. A missing file is
+// not an error - there's simply no drift to detect against yet.
+func loadZoneState(path string) (*zoneState, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var st zoneState
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, err
+	}
+
+	return &st, nil
+}
+
+// saveZoneState writes st to path as indented JSON.
+func saveZoneState(path string, st zoneState) error {
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o600)
+}
+
+// driftReport compares the records a previous sync left behind against
+// what Cloudflare currently reports, returning the ones that changed
+// outside of cfzone. Records that vanished entirely are left to the
+// regular zonefile diff, which will already propose recreating them.
+// This is keyed by Cloudflare record ID, which is why -state is
+// restricted to -provider=cloudflare in parseArguments: other providers
+// have no equivalent stable identity to match records against.
+func driftReport(previous *zoneState, current recordCollection) []stateRecord {
+	if previous == nil {
+		return nil
+	}
+
+	currentByID := make(map[string]cloudflare.DNSRecord, len(current))
+	for _, r := range current {
+		currentByID[r.ID] = r
+	}
+
+	var drifted []stateRecord
+	for _, want := range previous.Records {
+		got, ok := currentByID[want.ID]
+		if !ok {
+			continue
+		}
+
+		if newStateRecord(got) != want {
+			drifted = append(drifted, want)
+		}
+	}
+
+	return drifted
+}
+
+// writeDriftReport prints a human-readable summary of drifted records.
+func writeDriftReport(w io.Writer, drifted []stateRecord) {
+	fmt.Fprintf(w, "Drift detected: %d record(s) changed outside cfzone since the last sync:\n", len(drifted))
+	for _, r := range drifted {
+		fmt.Fprintf(w, "  %s %s (id %s)\n", r.Type, r.Name, r.ID)
+	}
+}
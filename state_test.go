@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/cloudflare/cloudflare-go"
+)
+
+func TestDriftReportNilPrevious(t *testing.T) {
+	if drifted := driftReport(nil, recordCollection{}); drifted != nil {
+		t.Fatalf("expected nil drift report, got %+v", drifted)
+	}
+}
+
+func TestDriftReport(t *testing.T) {
+	previous := &zoneState{
+		Zone: "example.com",
+		Records: []stateRecord{
+			{ID: "1", Type: "A", Name: "www.example.com", Content: "10.0.0.1", TTL: 300},
+			{ID: "2", Type: "A", Name: "api.example.com", Content: "10.0.0.2", TTL: 300},
+			{ID: "3", Type: "A", Name: "gone.example.com", Content: "10.0.0.3", TTL: 300},
+		},
+	}
+
+	current := recordCollection{
+		// Unchanged.
+		{ID: "1", Type: "A", Name: "www.example.com", Content: "10.0.0.1", TTL: 300},
+		// Content changed outside of cfzone - this is drift.
+		{ID: "2", Type: "A", Name: "api.example.com", Content: "10.0.0.99", TTL: 300},
+		// ID "3" is missing entirely - left to the regular zonefile diff,
+		// not reported as drift.
+	}
+
+	drifted := driftReport(previous, current)
+
+	if len(drifted) != 1 {
+		t.Fatalf("expected 1 drifted record, got %d: %+v", len(drifted), drifted)
+	}
+	if drifted[0].ID != "2" || drifted[0].Content != "10.0.0.2" {
+		t.Fatalf("unexpected drifted record: %+v", drifted[0])
+	}
+}
+
+func TestNewStateRecord(t *testing.T) {
+	proxied := true
+	r := cloudflare.DNSRecord{ID: "1", Type: "A", Name: "www.example.com", Content: "10.0.0.1", TTL: 300, Proxied: &proxied}
+
+	st := newStateRecord(r)
+	want := stateRecord{ID: "1", Type: "A", Name: "www.example.com", Content: "10.0.0.1", TTL: 300, Proxied: true}
+	if st != want {
+		t.Fatalf("got %+v, want %+v", st, want)
+	}
+
+	unproxied := newStateRecord(cloudflare.DNSRecord{ID: "2", Type: "A", Name: "api.example.com"})
+	if unproxied.Proxied {
+		t.Fatal("expected Proxied to default to false when nil")
+	}
+}